@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	admissionMaxNameLength = 64
+	admissionRateLimit     = 5 // requests per second, per source IP
+	admissionRateBurst     = 10
+
+	// admissionLimiterIdleTTL bounds how long a per-IP limiter is kept once
+	// that IP stops submitting, so a public-facing lobby doesn't accumulate
+	// one entry per distinct client IP forever.
+	admissionLimiterIdleTTL = 10 * time.Minute
+)
+
+// AdmissionResponse mirrors the JSON body returned by both the built-in
+// and external admission validators.
+type AdmissionResponse struct {
+	Allowed bool            `json:"allowed"`
+	Reason  string          `json:"reason,omitempty"`
+	Patch   json.RawMessage `json:"patch,omitempty"`
+}
+
+// AdmissionValidator is modeled after Kubernetes admission webhooks: given
+// a proposed Server, it can deny the request or return a JSON-merge-patch
+// fragment to mutate it before it is persisted.
+type AdmissionValidator interface {
+	Validate(ctx context.Context, server *Server) (*AdmissionResponse, error)
+}
+
+// ADMISSION_VALIDATORS runs in order for every POST /server. The built-in
+// localAdmissionValidator always runs first so baseline checks can't be
+// bypassed by a misbehaving external validator.
+var ADMISSION_VALIDATORS []AdmissionValidator
+
+func init_admission(admissionURLs ArrayOfParams, timeout time.Duration) {
+	ADMISSION_VALIDATORS = []AdmissionValidator{newLocalAdmissionValidator()}
+
+	for _, url := range admissionURLs {
+		ADMISSION_VALIDATORS = append(ADMISSION_VALIDATORS, &httpAdmissionValidator{
+			url:     url,
+			client:  &http.Client{Timeout: timeout},
+			timeout: timeout,
+		})
+		INFO.Printf("%s registered as admission validator", url)
+	}
+}
+
+// RunAdmission calls every registered validator in order, applying any
+// mutating patch before the next validator sees the Server. It returns the
+// reason for the first denial, if any.
+func RunAdmission(ctx context.Context, server *Server) (allowed bool, reason string, err error) {
+	for _, v := range ADMISSION_VALIDATORS {
+		resp, err := v.Validate(ctx, server)
+		if err != nil {
+			return false, "", fmt.Errorf("admission validator error: %w", err)
+		}
+
+		if !resp.Allowed {
+			return false, resp.Reason, nil
+		}
+
+		if len(resp.Patch) > 0 {
+			if err := applyMergePatch(server, resp.Patch); err != nil {
+				return false, "", fmt.Errorf("applying admission patch: %w", err)
+			}
+		}
+	}
+
+	return true, "", nil
+}
+
+// applyMergePatch applies a JSON-merge-patch (RFC 7396) style fragment to
+// server: present keys overwrite, null keys delete.
+func applyMergePatch(server *Server, patch json.RawMessage) error {
+	base, err := json.Marshal(server)
+	if err != nil {
+		return err
+	}
+
+	var merged map[string]interface{}
+	if err := json.Unmarshal(base, &merged); err != nil {
+		return err
+	}
+
+	var fragment map[string]interface{}
+	if err := json.Unmarshal(patch, &fragment); err != nil {
+		return err
+	}
+
+	for k, v := range fragment {
+		if v == nil {
+			delete(merged, k)
+			continue
+		}
+		merged[k] = v
+	}
+
+	out, err := json.Marshal(merged)
+	if err != nil {
+		return err
+	}
+
+	// Unmarshal into a zero-valued Server rather than the existing *server so
+	// that keys deleted above actually reset to their zero value instead of
+	// retaining their pre-patch contents.
+	var patched Server
+	if err := json.Unmarshal(out, &patched); err != nil {
+		return err
+	}
+
+	// base never round-trips json:"-" fields, so they're absent from merged
+	// and patched has zeroed them regardless of what the patch contained.
+	// Carry them over from the pre-patch server explicitly.
+	patched.ClientIP = server.ClientIP
+
+	*server = patched
+	return nil
+}
+
+// httpAdmissionValidator posts the proposed Server to an operator-supplied
+// -admission-url and interprets the response as an AdmissionResponse.
+type httpAdmissionValidator struct {
+	url     string
+	client  *http.Client
+	timeout time.Duration
+}
+
+func (v *httpAdmissionValidator) Validate(ctx context.Context, server *Server) (*AdmissionResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, v.timeout)
+	defer cancel()
+
+	body, err := json.Marshal(server)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("admission validator %s: %w", v.url, err)
+	}
+	defer resp.Body.Close()
+
+	var out AdmissionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("admission validator %s returned invalid JSON: %w", v.url, err)
+	}
+
+	return &out, nil
+}
+
+// rateLimiterEntry pairs a per-IP token bucket with the last time it was
+// used, so idle entries can be swept out of localAdmissionValidator.
+type rateLimiterEntry struct {
+	limiter  *rate.Limiter
+	lastUsed time.Time
+}
+
+// localAdmissionValidator implements the baseline checks the lobby has
+// always needed but never enforced: a sane name length, a game allow-list
+// loaded from config, basic IP sanity, and a per-source-IP rate limit.
+type localAdmissionValidator struct {
+	mu       sync.Mutex
+	limiters map[string]*rateLimiterEntry
+}
+
+func newLocalAdmissionValidator() *localAdmissionValidator {
+	return &localAdmissionValidator{
+		limiters: make(map[string]*rateLimiterEntry),
+	}
+}
+
+// limiterFor returns ip's token bucket, creating one if needed, and opportunistically
+// evicts any limiter idle for longer than admissionLimiterIdleTTL so the map
+// doesn't grow forever for a public-facing lobby.
+func (v *localAdmissionValidator) limiterFor(ip string) *rate.Limiter {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := time.Now()
+	for addr, entry := range v.limiters {
+		if now.Sub(entry.lastUsed) > admissionLimiterIdleTTL {
+			delete(v.limiters, addr)
+		}
+	}
+
+	entry, ok := v.limiters[ip]
+	if !ok {
+		entry = &rateLimiterEntry{limiter: rate.NewLimiter(rate.Limit(admissionRateLimit), admissionRateBurst)}
+		v.limiters[ip] = entry
+	}
+	entry.lastUsed = now
+
+	return entry.limiter
+}
+
+func (v *localAdmissionValidator) Validate(ctx context.Context, server *Server) (*AdmissionResponse, error) {
+	// Rate-limit first: every other check below does real work (config
+	// lookups, parsing), so checking it last would let a client flooding
+	// deliberately-invalid payloads dodge throttling entirely.
+	if !v.limiterFor(server.ClientIP).Allow() {
+		return &AdmissionResponse{Allowed: false, Reason: "rate limit exceeded for source IP"}, nil
+	}
+
+	if utf8.RuneCountInString(server.Name) > admissionMaxNameLength {
+		return &AdmissionResponse{Allowed: false, Reason: fmt.Sprintf("name exceeds %d characters", admissionMaxNameLength)}, nil
+	}
+
+	if cfg := CONFIG.Load(); cfg != nil && len(cfg.AllowedGames) > 0 {
+		allowed := false
+		for _, g := range cfg.AllowedGames {
+			if g == server.Game {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return &AdmissionResponse{Allowed: false, Reason: fmt.Sprintf("game %q is not in the configured allow-list", server.Game)}, nil
+		}
+	}
+
+	if server.IP != "" && net.ParseIP(server.IP) == nil {
+		return &AdmissionResponse{Allowed: false, Reason: fmt.Sprintf("%q is not a valid IP address", server.IP)}, nil
+	}
+
+	if !ipAllowed(server.ClientIP) {
+		return &AdmissionResponse{Allowed: false, Reason: "source IP is denied by config"}, nil
+	}
+
+	return &AdmissionResponse{Allowed: true}, nil
+}
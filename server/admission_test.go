@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestApplyMergePatchOverwritesAndDeletes(t *testing.T) {
+	server := &Server{Name: "alice", Game: "ST", Region: "eu"}
+
+	err := applyMergePatch(server, []byte(`{"region":null,"game":"800XL"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if server.Region != "" {
+		t.Fatalf("expected region to be cleared by a null patch value, got %q", server.Region)
+	}
+	if server.Game != "800XL" {
+		t.Fatalf("expected game to be overwritten, got %q", server.Game)
+	}
+	if server.Name != "alice" {
+		t.Fatalf("expected name to be untouched, got %q", server.Name)
+	}
+}
+
+func TestApplyMergePatchPreservesClientIP(t *testing.T) {
+	server := &Server{Name: "alice", Game: "ST", ClientIP: "203.0.113.9"}
+
+	err := applyMergePatch(server, []byte(`{"region":"eu"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if server.ClientIP != "203.0.113.9" {
+		t.Fatalf("expected ClientIP to survive a patch that never mentions it, got %q", server.ClientIP)
+	}
+}
+
+func TestLocalAdmissionValidatorChecksRateLimitFirst(t *testing.T) {
+	v := newLocalAdmissionValidator()
+	ip := "203.0.113.1"
+
+	// Exhaust the burst so the next call would be throttled regardless of
+	// payload validity.
+	for i := 0; i < admissionRateBurst; i++ {
+		v.limiterFor(ip).Allow()
+	}
+
+	oversizedName := make([]byte, admissionMaxNameLength+1)
+	for i := range oversizedName {
+		oversizedName[i] = 'a'
+	}
+
+	resp, err := v.Validate(context.Background(), &Server{
+		Name:     string(oversizedName),
+		Game:     "ST",
+		ClientIP: ip,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Allowed {
+		t.Fatal("expected the request to be denied")
+	}
+	if resp.Reason != "rate limit exceeded for source IP" {
+		t.Fatalf("expected the rate limit to be checked before the name-length check, got reason %q", resp.Reason)
+	}
+}
+
+func TestLimiterForEvictsIdleEntries(t *testing.T) {
+	v := newLocalAdmissionValidator()
+
+	v.limiterFor("203.0.113.1")
+	v.limiters["203.0.113.1"].lastUsed = time.Now().Add(-admissionLimiterIdleTTL - time.Second)
+
+	v.limiterFor("203.0.113.2")
+
+	if _, ok := v.limiters["203.0.113.1"]; ok {
+		t.Fatal("expected the idle limiter to be evicted")
+	}
+	if _, ok := v.limiters["203.0.113.2"]; !ok {
+		t.Fatal("expected the active limiter to remain")
+	}
+}
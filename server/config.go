@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
+)
+
+// WebhookRetryConfig tunes the backoff/retry policy used by the
+// webhookWorkerPool.
+type WebhookRetryConfig struct {
+	BaseBackoff time.Duration `yaml:"base_backoff"`
+	MaxBackoff  time.Duration `yaml:"max_backoff"`
+	MaxAttempts int           `yaml:"max_attempts"`
+}
+
+// Config is everything that can be tuned via -config without recompiling.
+// Fields marked "live" below can change via hot-reload; others require a
+// restart and only log a WARN when changed in the file.
+type Config struct {
+	SrvAddr      string                   `yaml:"srvaddr"`       // restart required
+	EvtAddrs     []string                 `yaml:"evtaddr"`       // live
+	LogLevel     string                   `yaml:"log_level"`     // live
+	WebhookRetry WebhookRetryConfig       `yaml:"webhook_retry"` // live
+	GameTTLs     map[string]time.Duration `yaml:"game_ttls"`     // live, consumed by the scheduler ticker
+	AllowedIPs   []string                 `yaml:"allowed_ips"`   // live
+	DeniedIPs    []string                 `yaml:"denied_ips"`    // live
+	AllowedGames []string                 `yaml:"allowed_games"` // live, enforced by the built-in admission validator
+}
+
+// CONFIG holds the currently-active Config, swapped atomically on reload
+// so handlers never see a partially-updated value.
+var CONFIG atomic.Pointer[Config]
+
+func loadConfigFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config %s: %w", path, err)
+	}
+
+	cfg := &Config{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// init_config loads path, stores it in CONFIG, applies it, and starts a
+// fsnotify watcher that hot-reloads on every write.
+func init_config(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	cfg, err := loadConfigFile(path)
+	if err != nil {
+		return err
+	}
+
+	CONFIG.Store(cfg)
+	applyConfig(cfg, nil)
+
+	go watchConfig(path)
+
+	return nil
+}
+
+func watchConfig(path string) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		ERROR.Printf("could not start config watcher: %v", err)
+		return
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(path); err != nil {
+		ERROR.Printf("could not watch config %s: %v", path, err)
+		return
+	}
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+
+			newCfg, err := loadConfigFile(path)
+			if err != nil {
+				WARN.Printf("config reload failed, keeping previous config: %v", err)
+				continue
+			}
+
+			applyConfig(newCfg, CONFIG.Load())
+			CONFIG.Store(newCfg)
+			INFO.Println("config reloaded")
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			WARN.Printf("config watcher error: %v", err)
+		}
+	}
+}
+
+// applyConfig pushes a newly loaded config's live fields out to the
+// subsystems that need them, and warns about fields that require a
+// restart to take effect.
+func applyConfig(newCfg, oldCfg *Config) {
+	if oldCfg != nil && newCfg.SrvAddr != oldCfg.SrvAddr {
+		WARN.Println("srvaddr changed in config but cannot be applied live; restart to pick it up")
+	}
+
+	if newCfg.LogLevel != "" {
+		DEBUG.SetActive(newCfg.LogLevel == "DEBUG")
+	}
+
+	reconcileWebhooks(oldCfg, newCfg)
+}
+
+// reconcileWebhooks re-validates newCfg.EvtAddrs and diffs it against the
+// currently-active EVTSERVER_WEBHOOKS, so newly-added endpoints start
+// receiving events immediately and removed ones stop, without touching
+// in-flight retries for endpoints that are unaffected.
+func reconcileWebhooks(oldCfg, newCfg *Config) {
+	before := webhookEndpoints()
+
+	added, removed := diffStrings(before, newCfg.EvtAddrs)
+
+	if len(added) > 0 {
+		init_webhook(ArrayOfParams(added))
+	}
+
+	if len(removed) > 0 {
+		webhooksMu.Lock()
+		for _, endpoint := range removed {
+			EVTSERVER_WEBHOOKS = removeString(EVTSERVER_WEBHOOKS, endpoint)
+		}
+		webhooksMu.Unlock()
+
+		for _, endpoint := range removed {
+			INFO.Printf("%s removed from eventserver webhooks", endpoint)
+		}
+	}
+}
+
+func diffStrings(before, after []string) (added, removed []string) {
+	beforeSet := make(map[string]bool, len(before))
+	for _, s := range before {
+		beforeSet[s] = true
+	}
+
+	afterSet := make(map[string]bool, len(after))
+	for _, s := range after {
+		afterSet[s] = true
+		if !beforeSet[s] {
+			added = append(added, s)
+		}
+	}
+
+	for _, s := range before {
+		if !afterSet[s] {
+			removed = append(removed, s)
+		}
+	}
+
+	return added, removed
+}
+
+func removeString(list []string, target string) []string {
+	out := list[:0]
+	for _, s := range list {
+		if s != target {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// defaultServerTTL is the TTL applied to a game with no game_ttls override,
+// including when no config is loaded at all.
+const defaultServerTTL = 5 * time.Minute
+
+// gameTTL returns the configured TTL for game, falling back to def when no
+// config is loaded or the game has no override.
+func gameTTL(game string, def time.Duration) time.Duration {
+	cfg := CONFIG.Load()
+	if cfg == nil {
+		return def
+	}
+
+	if ttl, ok := cfg.GameTTLs[game]; ok {
+		return ttl
+	}
+
+	return def
+}
+
+// ipAllowed reports whether ip is permitted to reach POST /server under the
+// configured allow/deny lists. An empty allow-list means "allow everything
+// not explicitly denied".
+func ipAllowed(ip string) bool {
+	cfg := CONFIG.Load()
+	if cfg == nil {
+		return true
+	}
+
+	for _, denied := range cfg.DeniedIPs {
+		if denied == ip {
+			return false
+		}
+	}
+
+	if len(cfg.AllowedIPs) == 0 {
+		return true
+	}
+
+	for _, allowed := range cfg.AllowedIPs {
+		if allowed == ip {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiffStrings(t *testing.T) {
+	before := []string{"http://a", "http://b"}
+	after := []string{"http://b", "http://c"}
+
+	added, removed := diffStrings(before, after)
+
+	if !reflect.DeepEqual(added, []string{"http://c"}) {
+		t.Fatalf("expected added=[http://c], got %v", added)
+	}
+	if !reflect.DeepEqual(removed, []string{"http://a"}) {
+		t.Fatalf("expected removed=[http://a], got %v", removed)
+	}
+}
+
+func TestRemoveString(t *testing.T) {
+	list := []string{"a", "b", "c"}
+
+	got := removeString(list, "b")
+
+	if !reflect.DeepEqual(got, []string{"a", "c"}) {
+		t.Fatalf("expected [a c], got %v", got)
+	}
+}
+
+func TestGameTTLFallsBackWithoutConfig(t *testing.T) {
+	if got := gameTTL("ST", defaultServerTTL); got != defaultServerTTL {
+		t.Fatalf("expected default TTL %v with no config loaded, got %v", defaultServerTTL, got)
+	}
+}
+
+func TestGameTTLUsesConfiguredOverride(t *testing.T) {
+	cfg := &Config{GameTTLs: map[string]time.Duration{"ST": 30 * time.Second}}
+	CONFIG.Store(cfg)
+	defer CONFIG.Store(nil)
+
+	if got := gameTTL("ST", defaultServerTTL); got != 30*time.Second {
+		t.Fatalf("expected configured override of 30s, got %v", got)
+	}
+	if got := gameTTL("800XL", defaultServerTTL); got != defaultServerTTL {
+		t.Fatalf("expected default TTL for a game with no override, got %v", got)
+	}
+}
+
+func TestIPAllowedRespectsAllowAndDenyLists(t *testing.T) {
+	cfg := &Config{AllowedIPs: []string{"10.0.0.1"}, DeniedIPs: []string{"10.0.0.2"}}
+	CONFIG.Store(cfg)
+	defer CONFIG.Store(nil)
+
+	if !ipAllowed("10.0.0.1") {
+		t.Fatal("expected an explicitly allowed IP to be allowed")
+	}
+	if ipAllowed("10.0.0.3") {
+		t.Fatal("expected an IP missing from a non-empty allow-list to be denied")
+	}
+	if ipAllowed("10.0.0.2") {
+		t.Fatal("expected an explicitly denied IP to be denied")
+	}
+}
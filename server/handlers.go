@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpsertServer registers or refreshes a game server. The proposed Server is
+// run through every registered admission validator (see admission.go)
+// before it is persisted; a denial short-circuits with a 4xx and the
+// validator's reason. PublishLobbyEvent is the single entry point this
+// (and DeleteServer) call to notify both /subscribe clients and the
+// webhook dispatcher of the change.
+func UpsertServer(c *gin.Context) {
+	var server Server
+	if err := c.BindJSON(&server); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	server.ClientIP = c.ClientIP()
+
+	allowed, reason, err := RunAdmission(c.Request.Context(), &server)
+	if err != nil {
+		ERROR.Printf("admission check failed for %s: %v", server.ClientIP, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "admission check failed"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": reason})
+		return
+	}
+
+	if DATABASE == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "database unavailable"})
+		return
+	}
+
+	DATABASE.Upsert(&server)
+	metricUpsertTotal.Inc()
+
+	PublishLobbyEvent(lobbyEvent{Type: lobbyEventUpsert, Server: &server})
+
+	c.JSON(http.StatusOK, server)
+}
+
+// DeleteServer removes a previously registered server identified by its
+// game and name.
+func DeleteServer(c *gin.Context) {
+	var req Server
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if DATABASE == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "database unavailable"})
+		return
+	}
+
+	removed, ok := DATABASE.Delete(req.Game, req.Name)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "server not registered"})
+		return
+	}
+	metricDeleteTotal.Inc()
+
+	PublishLobbyEvent(lobbyEvent{Type: lobbyEventDelete, Server: removed})
+
+	c.JSON(http.StatusOK, gin.H{"deleted": true})
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Server is a single registered game server, as submitted to POST /server
+// and returned by the /view family of endpoints and the /subscribe event
+// stream.
+type Server struct {
+	Name     string    `json:"name" binding:"required"`
+	Game     string    `json:"game" binding:"required"`
+	Region   string    `json:"region,omitempty"`
+	IP       string    `json:"ip"`
+	Port     int       `json:"port"`
+	ClientIP string    `json:"-"`
+	LastSeen time.Time `json:"last_seen,omitempty"`
+}
+
+func serverKey(game, name string) string {
+	return game + "|" + name
+}
+
+// lobbyDB is the in-memory store of currently registered servers. Entries
+// are pruned by the scheduler ticker once they exceed their game's
+// configured TTL (see gameTTL in config.go).
+type lobbyDB struct {
+	mu      sync.RWMutex
+	servers map[string]*Server
+}
+
+func init_db() {
+	DATABASE = &lobbyDB{servers: make(map[string]*Server)}
+}
+
+// Close satisfies the graceful-shutdown path in main(); the in-memory store
+// has nothing to flush.
+func (db *lobbyDB) Close() error {
+	return nil
+}
+
+// Upsert inserts or refreshes server, stamping LastSeen so the TTL pruner
+// has an accurate clock to prune against.
+func (db *lobbyDB) Upsert(server *Server) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	server.LastSeen = time.Now()
+	db.servers[serverKey(server.Game, server.Name)] = server
+}
+
+// Delete removes the server identified by game/name, returning it so the
+// caller can publish a delete event with its final state.
+func (db *lobbyDB) Delete(game, name string) (*Server, bool) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	key := serverKey(game, name)
+	server, ok := db.servers[key]
+	if ok {
+		delete(db.servers, key)
+	}
+	return server, ok
+}
+
+// ListMinimised returns the registered servers, optionally filtered by game
+// and/or region, in the shape used by /view and the /subscribe snapshot.
+func (db *lobbyDB) ListMinimised(game, region string) []*Server {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	out := make([]*Server, 0, len(db.servers))
+	for _, server := range db.servers {
+		if game != "" && server.Game != game {
+			continue
+		}
+		if region != "" && server.Region != region {
+			continue
+		}
+		out = append(out, server)
+	}
+	return out
+}
+
+// CountByGame returns the number of currently registered servers per game,
+// consumed by the lobby_servers_registered gauge.
+func (db *lobbyDB) CountByGame() map[string]int {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	counts := make(map[string]int, len(db.servers))
+	for _, server := range db.servers {
+		counts[server.Game]++
+	}
+	return counts
+}
+
+// PruneExpired removes and returns every server that has not been
+// refreshed within its game's configured TTL (falling back to def), called
+// once per tick from the scheduler.
+func (db *lobbyDB) PruneExpired(def time.Duration) []*Server {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	now := time.Now()
+	var expired []*Server
+	for key, server := range db.servers {
+		if now.Sub(server.LastSeen) > gameTTL(server.Game, def) {
+			expired = append(expired, server)
+			delete(db.servers, key)
+		}
+	}
+	return expired
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestDB() *lobbyDB {
+	return &lobbyDB{servers: make(map[string]*Server)}
+}
+
+func TestLobbyDBUpsertAndDelete(t *testing.T) {
+	db := newTestDB()
+
+	db.Upsert(&Server{Name: "alice", Game: "ST"})
+	if got := db.CountByGame(); got["ST"] != 1 {
+		t.Fatalf("expected 1 ST server, got %d", got["ST"])
+	}
+
+	removed, ok := db.Delete("ST", "alice")
+	if !ok || removed.Name != "alice" {
+		t.Fatalf("expected to delete alice, got %v, ok=%v", removed, ok)
+	}
+
+	if got := db.CountByGame(); got["ST"] != 0 {
+		t.Fatalf("expected 0 ST servers after delete, got %d", got["ST"])
+	}
+}
+
+func TestLobbyDBListMinimisedFilters(t *testing.T) {
+	db := newTestDB()
+	db.Upsert(&Server{Name: "alice", Game: "ST", Region: "eu"})
+	db.Upsert(&Server{Name: "bob", Game: "ST", Region: "us"})
+	db.Upsert(&Server{Name: "carol", Game: "800XL", Region: "eu"})
+
+	if got := db.ListMinimised("ST", ""); len(got) != 2 {
+		t.Fatalf("expected 2 ST servers, got %d", len(got))
+	}
+
+	if got := db.ListMinimised("ST", "eu"); len(got) != 1 || got[0].Name != "alice" {
+		t.Fatalf("expected only alice for ST/eu, got %v", got)
+	}
+}
+
+func TestLobbyDBPruneExpired(t *testing.T) {
+	db := newTestDB()
+
+	fresh := &Server{Name: "fresh", Game: "ST"}
+	db.Upsert(fresh)
+
+	stale := &Server{Name: "stale", Game: "ST"}
+	db.Upsert(stale)
+	db.servers[serverKey("ST", "stale")].LastSeen = time.Now().Add(-time.Hour)
+
+	expired := db.PruneExpired(5 * time.Minute)
+
+	if len(expired) != 1 || expired[0].Name != "stale" {
+		t.Fatalf("expected only stale to be pruned, got %v", expired)
+	}
+	if _, ok := db.servers[serverKey("ST", "fresh")]; !ok {
+		t.Fatal("expected fresh server to survive pruning")
+	}
+}
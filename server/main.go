@@ -2,22 +2,27 @@ package main
 
 import (
 	"bytes"
+	"context"
 	_ "embed"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"net"
+	"net/http"
 	"net/url"
 	"os"
 	"os/signal"
 	"runtime"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"github.com/madflojo/tasks"
+	"golang.org/x/sync/errgroup"
 )
 
 var (
@@ -35,8 +40,26 @@ var (
 	TIME               uint64
 	STARTEDON          time.Time
 	EVTSERVER_WEBHOOKS []string
+	WEBHOOKS           *webhookWorkerPool
 )
 
+// webhooksMu guards EVTSERVER_WEBHOOKS, which is appended to at startup and
+// mutated again on every config hot-reload (reconcileWebhooks, running on
+// the watchConfig goroutine) while webhookWorkerPool.Dispatch concurrently
+// ranges over it for every published lobby event.
+var webhooksMu sync.RWMutex
+
+// webhookEndpoints returns a snapshot of the currently active webhook
+// endpoints, safe to range over without holding webhooksMu.
+func webhookEndpoints() []string {
+	webhooksMu.RLock()
+	defer webhooksMu.RUnlock()
+
+	out := make([]string, len(EVTSERVER_WEBHOOKS))
+	copy(out, EVTSERVER_WEBHOOKS)
+	return out
+}
+
 const (
 	VERSION   = "5.5.1rc/multiple-web-hooks"
 	STRINGVER = "fujinet persistent lobby  " + VERSION + "/" + runtime.GOOS + " (c) Roger Sen 2025"
@@ -53,12 +76,26 @@ func main() {
 	var srvaddr string
 	var evtaddrs ArrayOfParams
 	var help, version bool
+	var shutdownTimeout time.Duration
+	var webhookDLQ string
+	var webhookMaxAttempts int
+	var metricsAddr string
+	var configPath string
+	var admissionURLs ArrayOfParams
+	var admissionTimeout time.Duration
 
 	flag.StringVar(&srvaddr, "srvaddr", ":8080", "<address:port> for http server")
 	flag.Var(&evtaddrs, "evtaddr", "<http> for event server webhook (multiple values accepted)")
 
 	flag.BoolVar(&version, "version", false, "show current version")
 	flag.BoolVar(&help, "help", false, "show this help")
+	flag.DurationVar(&shutdownTimeout, "shutdown-timeout", 15*time.Second, "how long to wait for subsystems to stop cleanly before forcing exit")
+	flag.StringVar(&webhookDLQ, "webhook-dlq", "", "path to the JSON-lines dead-letter file for permanently-failed webhook deliveries (disabled if empty)")
+	flag.IntVar(&webhookMaxAttempts, "webhook-max-attempts", 6, "max delivery attempts per webhook before it is sent to the dead-letter file")
+	flag.StringVar(&metricsAddr, "metrics-addr", "", "<address:port> to expose /metrics on, separate from srvaddr (served on srvaddr if empty)")
+	flag.StringVar(&configPath, "config", "", "path to a lobby.yaml config file, hot-reloaded on change (flags above are used as defaults)")
+	flag.Var(&admissionURLs, "admission-url", "<http> for an external admission validator (multiple values accepted)")
+	flag.DurationVar(&admissionTimeout, "admission-timeout", 500*time.Millisecond, "timeout for each admission validator call")
 
 	flag.Parse()
 
@@ -73,14 +110,28 @@ func main() {
 	}
 
 	init_logger()
-	init_os_signal()
+	ctx, stop := init_os_signal()
+	defer stop()
+
+	if err := init_config(configPath); err != nil {
+		ERROR.Printf("could not load config: %v", err)
+		os.Exit(1)
+	}
+
+	if cfg := CONFIG.Load(); cfg != nil && cfg.SrvAddr != "" {
+		srvaddr = cfg.SrvAddr
+	}
+
 	init_scheduler()
 	init_time()
 	init_db()
 	init_html(srvaddr)
 	init_webhook(evtaddrs)
+	WEBHOOKS = newWebhookWorkerPool(webhookDLQ, webhookMaxAttempts)
+	init_admission(admissionURLs, admissionTimeout)
 
 	router := gin.Default()
+	router.Use(metricsMiddleware())
 
 	router.GET("/", ShowServersHtml)
 	router.GET("/docs", ShowDocs)
@@ -89,9 +140,105 @@ func main() {
 	router.GET("/version", ShowStatus)
 	router.POST("/server", UpsertServer)
 	router.DELETE("/server", DeleteServer)
+	router.POST("/webhooks/replay", ReplayWebhookDLQ)
+	router.GET("/subscribe", SubscribeServers)
+
+	// /metrics is always reachable; -metrics-addr only moves it onto its
+	// own bind address for operators who want it off the public srvaddr,
+	// it doesn't gate whether the endpoint exists at all.
+	var metricsSrv *http.Server
+	if metricsAddr != "" {
+		metricsRouter := gin.New()
+		metricsRouter.GET("/metrics", metricsHandler())
+		metricsSrv = &http.Server{
+			Addr:    metricsAddr,
+			Handler: metricsRouter,
+		}
+	} else {
+		router.GET("/metrics", metricsHandler())
+	}
+
+	srv := &http.Server{
+		Addr:    srvaddr,
+		Handler: router,
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+
+	if metricsSrv != nil {
+		g.Go(func() error {
+			INFO.Printf("metrics server listening on %s", metricsAddr)
+			if err := metricsSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return fmt.Errorf("metrics server: %w", err)
+			}
+			return nil
+		})
+	}
+
+	g.Go(func() error {
+		INFO.Printf("http server listening on %s", srvaddr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("http server: %w", err)
+		}
+		return nil
+	})
+
+	g.Go(func() error {
+		WEBHOOKS.Run(gctx)
+		return nil
+	})
+
+	g.Go(func() error {
+		<-gctx.Done()
+		WARN.Println("shutdown signal received, stopping subsystems")
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			ERROR.Printf("http server did not shut down cleanly: %v", err)
+		} else {
+			INFO.Println("http server stopped")
+		}
 
-	router.Run(srvaddr)
+		if metricsSrv != nil {
+			if err := metricsSrv.Shutdown(shutdownCtx); err != nil {
+				ERROR.Printf("metrics server did not shut down cleanly: %v", err)
+			} else {
+				INFO.Println("metrics server stopped")
+			}
+		}
+
+		if SCHEDULER != nil {
+			SCHEDULER.Stop()
+			INFO.Println("scheduler stopped")
+		}
+
+		if DATABASE != nil {
+			if err := DATABASE.Close(); err != nil {
+				ERROR.Printf("database did not close cleanly: %v", err)
+			} else {
+				INFO.Println("database stopped")
+			}
+		}
+
+		WEBHOOKS.Stop()
+		INFO.Println("webhook workers stopped")
+
+		if shutdownCtx.Err() != nil {
+			ERROR.Println("shutdown grace period exceeded, forcing exit")
+			os.Exit(1)
+		}
+
+		return nil
+	})
+
+	if err := g.Wait(); err != nil {
+		ERROR.Printf("fatal error: %v", err)
+		os.Exit(1)
+	}
 
+	INFO.Println("shutdown complete")
 }
 
 /*
@@ -115,7 +262,7 @@ func init_logger() {
 }
 
 func init_scheduler() error {
-	SCHEDULER := tasks.New()
+	SCHEDULER = tasks.New()
 
 	TIME = 0
 
@@ -135,33 +282,23 @@ func ticker(s string) func() error {
 
 		TIME += 1
 
+		if DATABASE != nil {
+			for _, expired := range DATABASE.PruneExpired(defaultServerTTL) {
+				INFO.Printf("%s (%s) pruned after exceeding its TTL", expired.Name, expired.Game)
+				metricDeleteTotal.Inc()
+				PublishLobbyEvent(lobbyEvent{Type: lobbyEventDelete, Server: expired})
+			}
+		}
+
 		return nil
 	}
 }
 
-func init_os_signal() {
-
-	sigchnl := make(chan os.Signal, 1)
-	signal.Notify(sigchnl)
-
-	go SignalHandler(sigchnl)
-}
-
-func SignalHandler(sigchan chan os.Signal) {
-
-	for {
-		signal := <-sigchan
-
-		switch signal {
-
-		case syscall.SIGTERM:
-			WARN.Println("Got SIGTERM. Program will terminate cleanly now.")
-			os.Exit(143)
-		case syscall.SIGINT:
-			WARN.Println("Got SIGINT. Program will terminate cleanly now.")
-			os.Exit(137)
-		}
-	}
+// init_os_signal derives a cancelable context from SIGINT/SIGTERM so
+// subsystems in main() can shut down cleanly via the errgroup instead of
+// exiting the process immediately.
+func init_os_signal() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 }
 
 // save start of the program time
@@ -201,8 +338,8 @@ func init_webhook(evtaddrs ArrayOfParams) {
 
 		url, err := url.Parse(evtaddr)
 		if err != nil {
-			WARN.Printf("%s is not a valid url for the event server webhook. Eventserver won't be used", evtaddr)
-			return
+			WARN.Printf("%s is not a valid url for the event server webhook, skipping it", evtaddr)
+			continue
 		}
 
 		_, err = net.LookupIP(url.Host)
@@ -212,7 +349,10 @@ func init_webhook(evtaddrs ArrayOfParams) {
 		}
 
 		INFO.Printf("%s will be used as eventserver webhook", evtaddr)
+
+		webhooksMu.Lock()
 		EVTSERVER_WEBHOOKS = append(EVTSERVER_WEBHOOKS, evtaddr)
+		webhooksMu.Unlock()
 
 	}
 
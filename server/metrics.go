@@ -0,0 +1,108 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	metricUpsertTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "lobby_upsert_total",
+		Help: "Total number of server upserts accepted.",
+	})
+
+	metricDeleteTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "lobby_delete_total",
+		Help: "Total number of server deletions accepted.",
+	})
+
+	metricViewRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lobby_view_requests_total",
+		Help: "Total number of requests to the server-list views.",
+	}, []string{"route"})
+
+	metricWebhookDispatchTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "lobby_webhook_dispatch_total",
+		Help: "Total number of webhook deliveries attempted, by endpoint and result.",
+	}, []string{"endpoint", "result"})
+
+	metricServersRegistered = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "lobby_servers_registered",
+		Help: "Number of servers currently registered, by game.",
+	}, []string{"game"})
+
+	metricUptimeSeconds = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "lobby_uptime_seconds",
+		Help: "Seconds since the lobby process started.",
+	})
+
+	metricWebhookCircuitState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "lobby_webhook_circuit_state",
+		Help: "Circuit breaker state per webhook endpoint (0=closed, 1=half-open, 2=open).",
+	}, []string{"endpoint"})
+
+	metricRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "lobby_request_duration_seconds",
+		Help:    "HTTP request latency, by route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route"})
+)
+
+// metricsMiddleware records lobby_request_duration_seconds for every
+// request routed through gin.
+func metricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metricRequestDuration.WithLabelValues(route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// refreshGauges recomputes the gauges that reflect current state rather
+// than counting events, just before they are scraped.
+func refreshGauges() {
+	metricUptimeSeconds.Set(time.Since(STARTEDON).Seconds())
+
+	if DATABASE != nil {
+		// Reset first so a game whose last server was deleted/pruned since
+		// the previous scrape drops out of the metric instead of keeping
+		// its last nonzero value forever.
+		metricServersRegistered.Reset()
+		for game, count := range DATABASE.CountByGame() {
+			metricServersRegistered.WithLabelValues(game).Set(float64(count))
+		}
+	}
+
+	if WEBHOOKS != nil {
+		for _, s := range WEBHOOKS.Stats() {
+			var state float64
+			switch s.State {
+			case "half-open":
+				state = 1
+			case "open":
+				state = 2
+			}
+			metricWebhookCircuitState.WithLabelValues(s.Endpoint).Set(state)
+		}
+	}
+}
+
+// metricsHandler wraps promhttp.Handler() so the gauges are refreshed on
+// every scrape rather than only at dispatch time.
+func metricsHandler() gin.HandlerFunc {
+	h := promhttp.Handler()
+	return func(c *gin.Context) {
+		refreshGauges()
+		h.ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRefreshGaugesDropsDeletedGames(t *testing.T) {
+	prevDB := DATABASE
+	defer func() { DATABASE = prevDB }()
+
+	DATABASE = &lobbyDB{servers: make(map[string]*Server)}
+	DATABASE.Upsert(&Server{Name: "alice", Game: "ST"})
+
+	refreshGauges()
+	if got := testutil.ToFloat64(metricServersRegistered.WithLabelValues("ST")); got != 1 {
+		t.Fatalf("expected 1 server registered for ST after upsert, got %v", got)
+	}
+
+	DATABASE.Delete("ST", "alice")
+
+	refreshGauges()
+	if got := testutil.ToFloat64(metricServersRegistered.WithLabelValues("ST")); got != 0 {
+		t.Fatalf("expected the gauge to drop to 0 once the last ST server is deleted, got %v", got)
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	subscriberSendBuffer = 32
+	subscriberPingPeriod = 30 * time.Second
+)
+
+// lobbyEventType distinguishes the kinds of frames streamed over
+// /subscribe, and doubles as the event fired at the webhook dispatcher so
+// both consumers share a single event source.
+type lobbyEventType string
+
+const (
+	lobbyEventSnapshot lobbyEventType = "snapshot"
+	lobbyEventUpsert   lobbyEventType = "upsert"
+	lobbyEventDelete   lobbyEventType = "delete"
+)
+
+// lobbyEvent is published whenever UpsertServer or DeleteServer changes the
+// registered server list.
+type lobbyEvent struct {
+	Type    lobbyEventType `json:"type"`
+	Server  *Server        `json:"server,omitempty"`
+	Servers []*Server      `json:"servers,omitempty"`
+}
+
+// subscriber is a single /subscribe WebSocket connection with its own
+// optional game/region filter and a buffered outbound queue.
+type subscriber struct {
+	conn   *websocket.Conn
+	send   chan []byte
+	game   string
+	region string
+}
+
+func (s *subscriber) matches(evt lobbyEvent) bool {
+	if evt.Server == nil {
+		return true
+	}
+	if s.game != "" && evt.Server.Game != s.game {
+		return false
+	}
+	if s.region != "" && evt.Server.Region != s.region {
+		return false
+	}
+	return true
+}
+
+// lobbyHub fans lobbyEvents published via PublishLobbyEvent out to every
+// connected /subscribe client.
+type lobbyHub struct {
+	mu          sync.Mutex
+	subscribers map[*subscriber]bool
+}
+
+var HUB = &lobbyHub{subscribers: make(map[*subscriber]bool)}
+
+func (h *lobbyHub) add(s *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subscribers[s] = true
+}
+
+func (h *lobbyHub) remove(s *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subscribers, s)
+	close(s.send)
+}
+
+func (h *lobbyHub) broadcast(evt lobbyEvent) {
+	payload, err := json.Marshal(evt)
+	if err != nil {
+		ERROR.Printf("could not marshal lobby event: %v", err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for s := range h.subscribers {
+		if !s.matches(evt) {
+			continue
+		}
+
+		select {
+		case s.send <- payload:
+		default:
+			WARN.Println("subscriber buffer full, dropping slow /subscribe client")
+			go s.conn.Close()
+		}
+	}
+}
+
+// PublishLobbyEvent is the single entry point UpsertServer/DeleteServer
+// call to notify both /subscribe clients and the webhook dispatcher of a
+// change, so there is one event source for both.
+func PublishLobbyEvent(evt lobbyEvent) {
+	HUB.broadcast(evt)
+
+	if WEBHOOKS == nil || evt.Server == nil {
+		return
+	}
+
+	payload, err := json.Marshal(evt)
+	if err != nil {
+		ERROR.Printf("could not marshal lobby event for webhook dispatch: %v", err)
+		return
+	}
+	WEBHOOKS.Dispatch(payload)
+}
+
+var subscribeUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// SubscribeServers upgrades to a WebSocket, sends a snapshot of the current
+// (optionally filtered) server list, and then streams upsert/delete frames
+// as they happen. A ping frame every 30s keeps NATs from closing the
+// connection.
+func SubscribeServers(c *gin.Context) {
+	conn, err := subscribeUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		WARN.Printf("/subscribe upgrade failed: %v", err)
+		return
+	}
+
+	sub := &subscriber{
+		conn:   conn,
+		send:   make(chan []byte, subscriberSendBuffer),
+		game:   c.Query("game"),
+		region: c.Query("region"),
+	}
+
+	HUB.add(sub)
+	defer HUB.remove(sub)
+
+	var servers []*Server
+	if DATABASE != nil {
+		servers = DATABASE.ListMinimised(sub.game, sub.region)
+	}
+
+	snapshot, err := json.Marshal(lobbyEvent{Type: lobbyEventSnapshot, Servers: servers})
+	if err != nil {
+		ERROR.Printf("could not marshal /subscribe snapshot: %v", err)
+		conn.Close()
+		return
+	}
+
+	if err := conn.WriteMessage(websocket.TextMessage, snapshot); err != nil {
+		conn.Close()
+		return
+	}
+
+	go readPump(conn)
+	writePump(sub)
+}
+
+// readPump discards client frames but keeps reading so close/control
+// frames are handled and a dead connection is detected promptly.
+func readPump(conn *websocket.Conn) {
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+func writePump(sub *subscriber) {
+	ticker := time.NewTicker(subscriberPingPeriod)
+	defer ticker.Stop()
+	defer sub.conn.Close()
+
+	for {
+		select {
+		case payload, ok := <-sub.send:
+			if !ok {
+				sub.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := sub.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := sub.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,516 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// circuit breaker states for a single webhook endpoint.
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+const (
+	webhookFailureThreshold = 5
+	webhookFailureWindow    = 1 * time.Minute
+	webhookCooldown         = 30 * time.Second
+	webhookBaseBackoff      = 250 * time.Millisecond
+	webhookMaxBackoff       = 8 * time.Second
+	webhookQueueSize        = 256
+)
+
+// endpointStats tracks delivery counters for a single webhook endpoint,
+// surfaced on /version and used to drive the circuit breaker.
+type endpointStats struct {
+	mu               sync.Mutex
+	state            circuitState
+	consecutiveFails int
+	windowStart      time.Time
+	openedAt         time.Time
+	probing          bool
+	Sent             uint64
+	Failed           uint64
+	Tripped          uint64
+}
+
+// WebhookEndpointStats is the JSON-friendly snapshot returned by Stats().
+type WebhookEndpointStats struct {
+	Endpoint string `json:"endpoint"`
+	State    string `json:"state"`
+	Sent     uint64 `json:"sent"`
+	Failed   uint64 `json:"failed"`
+	Tripped  uint64 `json:"tripped"`
+}
+
+func (s circuitState) String() string {
+	switch s {
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// webhookJob is one attempt at delivering a payload to a single endpoint.
+type webhookJob struct {
+	Endpoint   string
+	DeliveryID string
+	Attempt    int
+	Payload    []byte
+}
+
+// deadLetter is the JSON-lines record written when a delivery permanently
+// fails, so an operator can inspect/replay it later.
+type deadLetter struct {
+	Endpoint   string    `json:"endpoint"`
+	DeliveryID string    `json:"delivery_id"`
+	Attempt    int       `json:"attempt"`
+	Payload    string    `json:"payload"`
+	FailedAt   time.Time `json:"failed_at"`
+}
+
+// endpointWorker pairs one endpoint's circuit-breaker state with its own
+// bounded queue and delivery goroutine, so a flaky endpoint generating
+// repeated retries can only ever fill its own queue, never starve delivery
+// to unrelated, healthy endpoints.
+type endpointWorker struct {
+	stats *endpointStats
+	queue chan webhookJob
+}
+
+// webhookWorkerPool owns a bounded per-endpoint work queue, a circuit
+// breaker per endpoint, and the dead-letter file for deliveries that
+// exhaust their retries. It is started and stopped alongside the other
+// subsystems in main().
+type webhookWorkerPool struct {
+	client          *http.Client
+	dlqPath         string
+	flagMaxAttempts int
+
+	mu        sync.Mutex
+	ctx       context.Context
+	endpoints map[string]*endpointWorker
+
+	wg   sync.WaitGroup
+	done chan struct{}
+}
+
+func newWebhookWorkerPool(dlqPath string, maxAttempts int) *webhookWorkerPool {
+	if maxAttempts <= 0 {
+		maxAttempts = 6
+	}
+
+	return &webhookWorkerPool{
+		client:          &http.Client{Timeout: 10 * time.Second},
+		dlqPath:         dlqPath,
+		flagMaxAttempts: maxAttempts,
+		endpoints:       make(map[string]*endpointWorker),
+		done:            make(chan struct{}),
+	}
+}
+
+// maxAttempts returns the active max-attempts policy, preferring a
+// hot-reloaded config value (webhook_retry.max_attempts) and falling back
+// to the -webhook-max-attempts flag value the pool was constructed with.
+func (p *webhookWorkerPool) maxAttempts() int {
+	if cfg := CONFIG.Load(); cfg != nil && cfg.WebhookRetry.MaxAttempts > 0 {
+		return cfg.WebhookRetry.MaxAttempts
+	}
+	return p.flagMaxAttempts
+}
+
+// workerFor returns the worker for endpoint, lazily creating its queue and,
+// if Run has already started, its consumer goroutine too.
+func (p *webhookWorkerPool) workerFor(endpoint string) *endpointWorker {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	w, ok := p.endpoints[endpoint]
+	if !ok {
+		w = &endpointWorker{
+			stats: &endpointStats{windowStart: time.Now()},
+			queue: make(chan webhookJob, webhookQueueSize),
+		}
+		p.endpoints[endpoint] = w
+		if p.ctx != nil {
+			p.startWorkerLocked(endpoint, w)
+		}
+	}
+	return w
+}
+
+// startWorkerLocked spawns endpoint's consumer goroutine. Callers must hold
+// p.mu and have already set p.ctx.
+func (p *webhookWorkerPool) startWorkerLocked(endpoint string, w *endpointWorker) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		for {
+			select {
+			case <-p.ctx.Done():
+				return
+			case job := <-w.queue:
+				p.deliver(p.ctx, job, w)
+			}
+		}
+	}()
+}
+
+// Dispatch enqueues payload for delivery to every registered endpoint. Each
+// endpoint has its own bounded queue, so a flaky endpoint filling up its
+// queue cannot cause drops for any other endpoint; Dispatch only drops (with
+// a WARN) into the specific endpoint's own full queue.
+func (p *webhookWorkerPool) Dispatch(payload []byte) {
+	for _, endpoint := range webhookEndpoints() {
+		job := webhookJob{
+			Endpoint:   endpoint,
+			DeliveryID: newDeliveryID(),
+			Attempt:    1,
+			Payload:    payload,
+		}
+
+		w := p.workerFor(endpoint)
+		select {
+		case w.queue <- job:
+		default:
+			WARN.Printf("webhook queue full for %s, dropping delivery", endpoint)
+		}
+	}
+}
+
+// Run starts a consumer goroutine for every endpoint known so far, starts
+// one for every endpoint discovered later via workerFor, and blocks until
+// ctx is canceled and every consumer has exited.
+func (p *webhookWorkerPool) Run(ctx context.Context) {
+	defer close(p.done)
+
+	p.mu.Lock()
+	p.ctx = ctx
+	for endpoint, w := range p.endpoints {
+		p.startWorkerLocked(endpoint, w)
+	}
+	p.mu.Unlock()
+
+	<-ctx.Done()
+	p.wg.Wait()
+}
+
+// Stop waits until Run has returned and every consumer goroutine has
+// exited. Jobs still queued at that point are not delivered.
+func (p *webhookWorkerPool) Stop() {
+	<-p.done
+}
+
+func (p *webhookWorkerPool) deliver(ctx context.Context, job webhookJob, w *endpointWorker) {
+	stats := w.stats
+
+	if !stats.allow() {
+		WARN.Printf("circuit open for %s, refusing delivery %s", job.Endpoint, job.DeliveryID)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.Endpoint, bytes.NewReader(job.Payload))
+	if err != nil {
+		ERROR.Printf("webhook request to %s could not be built: %v", job.Endpoint, err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Fujinet-Delivery-ID", job.DeliveryID)
+	req.Header.Set("X-Fujinet-Attempt", fmt.Sprintf("%d", job.Attempt))
+
+	resp, err := p.client.Do(req)
+	if err == nil {
+		resp.Body.Close()
+	}
+
+	if err != nil || resp.StatusCode >= 300 {
+		stats.recordFailure()
+		metricWebhookDispatchTotal.WithLabelValues(job.Endpoint, "failure").Inc()
+
+		if job.Attempt >= p.maxAttempts() {
+			ERROR.Printf("webhook delivery %s to %s permanently failed after %d attempts", job.DeliveryID, job.Endpoint, job.Attempt)
+			p.deadLetter(job)
+			return
+		}
+
+		backoff := nextBackoff(job.Attempt)
+		job.Attempt++
+
+		time.AfterFunc(backoff, func() {
+			select {
+			case w.queue <- job:
+			default:
+				WARN.Printf("webhook queue full for %s, dropping retry", job.Endpoint)
+			}
+		})
+		return
+	}
+
+	stats.recordSuccess()
+	metricWebhookDispatchTotal.WithLabelValues(job.Endpoint, "success").Inc()
+}
+
+// deadLetter appends a permanently-failed delivery to the configured
+// dead-letter file so it can be replayed later via POST /webhooks/replay.
+func (p *webhookWorkerPool) deadLetter(job webhookJob) {
+	if p.dlqPath == "" {
+		return
+	}
+
+	f, err := os.OpenFile(p.dlqPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		ERROR.Printf("could not open webhook DLQ file %s: %v", p.dlqPath, err)
+		return
+	}
+	defer f.Close()
+
+	entry := deadLetter{
+		Endpoint:   job.Endpoint,
+		DeliveryID: job.DeliveryID,
+		Attempt:    job.Attempt,
+		Payload:    string(job.Payload),
+		FailedAt:   time.Now(),
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		ERROR.Printf("could not marshal DLQ entry: %v", err)
+		return
+	}
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		ERROR.Printf("could not write DLQ entry: %v", err)
+	}
+}
+
+// Stats returns a snapshot of every endpoint's delivery counters and
+// circuit state, suitable for embedding in the /version response.
+func (p *webhookWorkerPool) Stats() []WebhookEndpointStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	out := make([]WebhookEndpointStats, 0, len(p.endpoints))
+	for endpoint, w := range p.endpoints {
+		w.stats.mu.Lock()
+		out = append(out, WebhookEndpointStats{
+			Endpoint: endpoint,
+			State:    w.stats.state.String(),
+			Sent:     w.stats.Sent,
+			Failed:   w.stats.Failed,
+			Tripped:  w.stats.Tripped,
+		})
+		w.stats.mu.Unlock()
+	}
+	return out
+}
+
+// allow reports whether the caller may dispatch now. In circuitOpen it lets
+// exactly one job through per cool-down period, flipping to circuitHalfOpen
+// as the single in-flight probe; every other job queued against a
+// half-open endpoint is refused until that probe reports success or
+// failure, so a burst of queued jobs can't all hit a just-recovering
+// endpoint at once.
+func (s *endpointStats) allow() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch s.state {
+	case circuitOpen:
+		if time.Since(s.openedAt) < webhookCooldown {
+			return false
+		}
+		s.state = circuitHalfOpen
+		s.probing = true
+		return true
+	case circuitHalfOpen:
+		if s.probing {
+			return false
+		}
+		s.probing = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (s *endpointStats) recordFailure() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Failed++
+
+	if s.state == circuitHalfOpen {
+		s.trip()
+		return
+	}
+
+	if time.Since(s.windowStart) > webhookFailureWindow {
+		s.windowStart = time.Now()
+		s.consecutiveFails = 0
+	}
+
+	s.consecutiveFails++
+	if s.consecutiveFails >= webhookFailureThreshold {
+		s.trip()
+	}
+}
+
+func (s *endpointStats) trip() {
+	s.state = circuitOpen
+	s.openedAt = time.Now()
+	s.probing = false
+	s.Tripped++
+}
+
+func (s *endpointStats) recordSuccess() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Sent++
+	s.consecutiveFails = 0
+	s.probing = false
+	s.state = circuitClosed
+}
+
+// webhookRetryPolicy returns the active backoff bounds, preferring the
+// hot-reloaded webhook_retry config and falling back to the package
+// defaults for any field the config leaves unset.
+func webhookRetryPolicy() (base, max time.Duration) {
+	base, max = webhookBaseBackoff, webhookMaxBackoff
+
+	cfg := CONFIG.Load()
+	if cfg == nil {
+		return base, max
+	}
+	if cfg.WebhookRetry.BaseBackoff > 0 {
+		base = cfg.WebhookRetry.BaseBackoff
+	}
+	if cfg.WebhookRetry.MaxBackoff > 0 {
+		max = cfg.WebhookRetry.MaxBackoff
+	}
+	return base, max
+}
+
+// nextBackoff returns an exponential backoff with jitter for the given
+// attempt number, bounded by the active webhookRetryPolicy.
+func nextBackoff(attempt int) time.Duration {
+	base, max := webhookRetryPolicy()
+
+	backoff := float64(base) * math.Pow(2, float64(attempt-1))
+	if backoff > float64(max) {
+		backoff = float64(max)
+	}
+
+	jitter, err := rand.Int(rand.Reader, big.NewInt(int64(backoff)/2+1))
+	if err != nil {
+		return time.Duration(backoff)
+	}
+
+	return time.Duration(backoff)/2 + time.Duration(jitter.Int64())
+}
+
+func newDeliveryID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// ReplayWebhookDLQ re-enqueues every entry currently sitting in the
+// dead-letter file. It is wired up as POST /webhooks/replay. Entries that
+// can't be re-queued (the target endpoint's queue is currently full) are
+// left in the DLQ file rather than being dropped on the floor: the file is
+// only removed once every well-formed entry in it has been successfully
+// re-queued. Malformed lines are skipped with a WARN and are not kept.
+func ReplayWebhookDLQ(c *gin.Context) {
+	if WEBHOOKS == nil || WEBHOOKS.dlqPath == "" {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "dead-letter queue is not configured"})
+		return
+	}
+
+	data, err := os.ReadFile(WEBHOOKS.dlqPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			c.JSON(http.StatusOK, gin.H{"replayed": 0})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	replayed := 0
+	var unreplayed []deadLetter
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var entry deadLetter
+		if err := json.Unmarshal(line, &entry); err != nil {
+			WARN.Printf("skipping malformed DLQ entry: %v", err)
+			continue
+		}
+
+		w := WEBHOOKS.workerFor(entry.Endpoint)
+		select {
+		case w.queue <- webhookJob{
+			Endpoint:   entry.Endpoint,
+			DeliveryID: newDeliveryID(),
+			Attempt:    1,
+			Payload:    []byte(entry.Payload),
+		}:
+			replayed++
+		default:
+			WARN.Printf("webhook queue full while replaying DLQ for %s, leaving it in the DLQ", entry.Endpoint)
+			unreplayed = append(unreplayed, entry)
+		}
+	}
+
+	if len(unreplayed) == 0 {
+		if err := os.Remove(WEBHOOKS.dlqPath); err != nil && !os.IsNotExist(err) {
+			WARN.Printf("could not clear DLQ file after replay: %v", err)
+		}
+	} else if err := rewriteDLQ(WEBHOOKS.dlqPath, unreplayed); err != nil {
+		ERROR.Printf("could not rewrite DLQ file with %d unreplayed entries: %v", len(unreplayed), err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"replayed": replayed, "remaining": len(unreplayed)})
+}
+
+// rewriteDLQ truncates path and writes entries back as JSON lines, used to
+// persist the entries ReplayWebhookDLQ could not re-queue.
+func rewriteDLQ(path string, entries []deadLetter) error {
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
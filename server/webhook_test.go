@@ -0,0 +1,116 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextBackoffBounds(t *testing.T) {
+	for attempt := 1; attempt <= 10; attempt++ {
+		d := nextBackoff(attempt)
+		if d <= 0 {
+			t.Fatalf("attempt %d: backoff must be positive, got %v", attempt, d)
+		}
+		if d > webhookMaxBackoff {
+			t.Fatalf("attempt %d: backoff %v exceeds max %v", attempt, d, webhookMaxBackoff)
+		}
+	}
+}
+
+func TestEndpointStatsTripsAfterThreshold(t *testing.T) {
+	s := &endpointStats{windowStart: time.Now()}
+
+	for i := 0; i < webhookFailureThreshold; i++ {
+		if s.state == circuitOpen {
+			t.Fatalf("circuit tripped early after %d failures", i)
+		}
+		s.recordFailure()
+	}
+
+	if s.state != circuitOpen {
+		t.Fatalf("expected circuit to be open after %d consecutive failures, got %v", webhookFailureThreshold, s.state)
+	}
+}
+
+func TestEndpointStatsHalfOpenAllowsOnlyOneProbe(t *testing.T) {
+	s := &endpointStats{state: circuitOpen, openedAt: time.Now().Add(-2 * webhookCooldown)}
+
+	if !s.allow() {
+		t.Fatal("expected the cool-down to admit a single probe")
+	}
+	if s.state != circuitHalfOpen {
+		t.Fatalf("expected state to flip to half-open, got %v", s.state)
+	}
+
+	if s.allow() {
+		t.Fatal("expected a second concurrent job to be refused while a probe is in flight")
+	}
+}
+
+func TestEndpointStatsRecordSuccessClosesCircuit(t *testing.T) {
+	s := &endpointStats{state: circuitHalfOpen, probing: true}
+
+	s.recordSuccess()
+
+	if s.state != circuitClosed {
+		t.Fatalf("expected circuit to close after a successful probe, got %v", s.state)
+	}
+	if s.probing {
+		t.Fatal("expected probing to be cleared after recordSuccess")
+	}
+}
+
+func TestEndpointStatsRecordFailureReopensFromHalfOpen(t *testing.T) {
+	s := &endpointStats{state: circuitHalfOpen, probing: true, windowStart: time.Now()}
+
+	s.recordFailure()
+
+	if s.state != circuitOpen {
+		t.Fatalf("expected a failed probe to reopen the circuit, got %v", s.state)
+	}
+	if s.probing {
+		t.Fatal("expected probing to be cleared after recordFailure")
+	}
+}
+
+func TestWebhookRetryPolicyUsesConfiguredOverride(t *testing.T) {
+	CONFIG.Store(&Config{WebhookRetry: WebhookRetryConfig{BaseBackoff: time.Second, MaxBackoff: 2 * time.Second}})
+	defer CONFIG.Store(nil)
+
+	base, max := webhookRetryPolicy()
+	if base != time.Second || max != 2*time.Second {
+		t.Fatalf("expected policy (1s, 2s) from config, got (%v, %v)", base, max)
+	}
+}
+
+func TestWorkerForGivesEachEndpointItsOwnQueue(t *testing.T) {
+	p := newWebhookWorkerPool("", 6)
+
+	a := p.workerFor("http://a.example")
+	b := p.workerFor("http://b.example")
+
+	if a.queue == b.queue {
+		t.Fatal("expected distinct queues per endpoint")
+	}
+
+	for i := 0; i < cap(a.queue); i++ {
+		a.queue <- webhookJob{Endpoint: "http://a.example"}
+	}
+
+	select {
+	case b.queue <- webhookJob{Endpoint: "http://b.example"}:
+	default:
+		t.Fatal("endpoint b's queue should still accept work while endpoint a's queue is full")
+	}
+}
+
+func TestWebhookWorkerPoolMaxAttemptsPrefersConfig(t *testing.T) {
+	p := newWebhookWorkerPool("", 6)
+
+	CONFIG.Store(&Config{WebhookRetry: WebhookRetryConfig{MaxAttempts: 3}})
+	defer CONFIG.Store(nil)
+
+	if got := p.maxAttempts(); got != 3 {
+		t.Fatalf("expected config override of 3, got %d", got)
+	}
+}